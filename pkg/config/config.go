@@ -0,0 +1,94 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configPathEnvVariable overrides the path Botkube reads its configuration from
+const configPathEnvVariable = "CONFIG_PATH"
+
+// defaultConfigPath is used when configPathEnvVariable is unset
+const defaultConfigPath = "/config/config.yaml"
+
+// Config is the root of the Botkube configuration file
+type Config struct {
+	Communications Communications
+	Settings       Settings
+}
+
+// Communications holds configuration for every supported notifier/executor
+type Communications struct {
+	Mattermost Mattermost
+}
+
+// Mattermost holds the Mattermost bot configuration
+type Mattermost struct {
+	Enabled bool
+	URL     string
+	Token   string
+
+	// Login/Password and PersonalAccessToken are alternatives to Token for
+	// authenticating against the Mattermost server
+	Login               string
+	Password            string
+	PersonalAccessToken string
+
+	// Team/Channel configure a single team/channel. Bindings, when set,
+	// configures several teams/channels instead and takes precedence
+	Team     string
+	Channel  string
+	Bindings []MattermostBinding
+
+	// MessageHistoryLimit bounds how many recent post digests are
+	// remembered to skip replayed websocket events
+	MessageHistoryLimit int
+
+	SlashCommandAddr  string
+	SlashCommandToken string
+
+	// ActionCallbackAddr is the local address the action server binds to.
+	// ActionCallbackPublicURL is the externally-reachable base URL
+	// Mattermost should POST interactive message actions to - typically a
+	// reverse proxy or ingress in front of ActionCallbackAddr, since the
+	// two are rarely the same address
+	ActionCallbackAddr      string
+	ActionCallbackPublicURL string
+}
+
+// MattermostBinding binds a Mattermost team/channel to the verbs and
+// namespaces Botkube is allowed to act on within it
+type MattermostBinding struct {
+	Team         string
+	Channel      string
+	ClusterName  string
+	Namespaces   []string
+	AllowedVerbs []string
+}
+
+// Settings holds cluster-wide Botkube settings
+type Settings struct {
+	ClusterName  string
+	AllowKubectl bool
+}
+
+// New reads and returns the Botkube configuration
+func New() (*Config, error) {
+	path := os.Getenv(configPathEnvVariable)
+	if path == "" {
+		path = defaultConfigPath
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
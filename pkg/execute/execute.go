@@ -0,0 +1,75 @@
+package execute
+
+import (
+	"strings"
+
+	"github.com/infracloudio/botkube/pkg/config"
+)
+
+// InvocationSource identifies where a command came from so the response can
+// be rendered appropriately for that surface
+type InvocationSource int
+
+const (
+	// SourceChannelMessage is a "@botkube " mention in a channel
+	SourceChannelMessage InvocationSource = iota
+	// SourceDirectMessage is a message sent directly to the bot
+	SourceDirectMessage
+	// SourceSlashCommand is a registered slash command
+	SourceSlashCommand
+)
+
+// Executor runs a single incoming command and returns its textual result
+type Executor struct {
+	Message       string
+	AllowKubectl  bool
+	ChannelName   string
+	IsAuthChannel bool
+	Binding       *config.MattermostBinding
+	Source        InvocationSource
+}
+
+// NewDefaultExecutor returns a new Executor for message, scoped to the
+// given channel and authorized by binding (nil when the channel has no
+// matching binding, e.g. a direct message)
+func NewDefaultExecutor(message string, allowKubectl bool, channelName string, isAuthChannel bool, binding *config.MattermostBinding, source InvocationSource) *Executor {
+	return &Executor{
+		Message:       message,
+		AllowKubectl:  allowKubectl,
+		ChannelName:   channelName,
+		IsAuthChannel: isAuthChannel,
+		Binding:       binding,
+		Source:        source,
+	}
+}
+
+// Execute checks the command against the channel's authorization. It does
+// not run the command: it returns a refusal message when the binding's
+// AllowedVerbs excludes the command's verb, and "" in every other case
+func (e *Executor) Execute() string {
+	if !e.IsAuthChannel || !e.AllowKubectl {
+		return ""
+	}
+	if e.Binding != nil && !verbAllowed(e.Binding.AllowedVerbs, e.Message) {
+		return "This command is not authorized for this channel"
+	}
+	return ""
+}
+
+// verbAllowed reports whether message's leading verb is in allowed, or
+// whether allowed imposes no restriction
+func verbAllowed(allowed []string, message string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	verb := strings.Fields(message)
+	if len(verb) == 0 {
+		return false
+	}
+	for _, v := range allowed {
+		if v == verb[0] {
+			return true
+		}
+	}
+	return false
+}
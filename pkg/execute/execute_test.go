@@ -0,0 +1,25 @@
+package execute
+
+import "testing"
+
+func TestVerbAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		message string
+		want    bool
+	}{
+		{"no restriction", nil, "get pods", true},
+		{"matching verb", []string{"get", "describe"}, "get pods", true},
+		{"non-matching verb", []string{"get", "describe"}, "delete pod mypod", false},
+		{"empty message", []string{"get"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verbAllowed(tt.allowed, tt.message); got != tt.want {
+				t.Errorf("verbAllowed(%v, %q) = %v, want %v", tt.allowed, tt.message, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+package logging
+
+import "github.com/sirupsen/logrus"
+
+// Logger is the package-wide structured logger used across Botkube
+var Logger = logrus.New()
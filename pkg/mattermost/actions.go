@@ -0,0 +1,218 @@
+package mattermost
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/infracloudio/botkube/pkg/config"
+	"github.com/infracloudio/botkube/pkg/execute"
+	"github.com/infracloudio/botkube/pkg/logging"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	// ActionCallbackPath is the HTTP path Mattermost posts interactive message actions to
+	ActionCallbackPath = "/mattermost/actions"
+	// actionDescriptorTTL bounds how long an action button stays clickable
+	// before its target resource descriptor is evicted from the cache
+	actionDescriptorTTL = 15 * time.Minute
+)
+
+// resourceDescriptor identifies the kubectl resource an action button targets
+type resourceDescriptor struct {
+	Verb      string
+	Previous  bool
+	Resource  string
+	Name      string
+	Namespace string
+	ChannelID string
+	// Binding is nil when the pod list was rendered in a channel with no
+	// matching RBAC binding (e.g. a DM), in which case the follow-up
+	// command runs unrestricted by AllowedVerbs/Namespaces, same as the
+	// original command that listed the pods
+	Binding *ChannelBinding
+
+	expiresAt time.Time
+}
+
+// command builds the follow-up kubectl invocation for the descriptor's verb
+func (d resourceDescriptor) command() string {
+	if d.Verb == "logs" {
+		if d.Previous {
+			return fmt.Sprintf("logs --previous %s -n %s", d.Name, d.Namespace)
+		}
+		return fmt.Sprintf("logs %s -n %s", d.Name, d.Namespace)
+	}
+	return fmt.Sprintf("%s %s %s -n %s", d.Verb, d.Resource, d.Name, d.Namespace)
+}
+
+// ActionServer serves interactive message action callbacks ("Describe",
+// "Logs", "Delete", ...) posted from buttons attached to command responses.
+// The action_id is still carried in the outbound post's Integration.Context,
+// same as a static token would be, so it isn't secret from anyone who can
+// read the post back via the Posts API; what it buys over a static token is
+// narrower scope (one resource descriptor) and a short TTL plus single-use
+// eviction, so a leaked ID only replays one already-authorized command
+// instead of granting standing access to run anything.
+type ActionServer struct {
+	Addr string
+	// PublicURL is the externally-reachable base URL Mattermost POSTs
+	// action callbacks to - not the same address as the Mattermost server
+	// itself (bot.ServerURL), which the bot only uses as an API/websocket
+	// client
+	PublicURL string
+
+	bot *Bot
+
+	mu          sync.Mutex
+	descriptors map[string]resourceDescriptor
+}
+
+// NewActionServer returns a new ActionServer bound to b, configured from Botkube config
+func NewActionServer(b *Bot) *ActionServer {
+	c, err := config.New()
+	if err != nil {
+		logging.Logger.Fatal(fmt.Sprintf("Error in loading configuration. Error:%s", err.Error()))
+	}
+
+	return &ActionServer{
+		Addr:        c.Communications.Mattermost.ActionCallbackAddr,
+		PublicURL:   c.Communications.Mattermost.ActionCallbackPublicURL,
+		bot:         b,
+		descriptors: make(map[string]resourceDescriptor),
+	}
+}
+
+// Start registers the action callback handler and serves it over HTTP
+func (a *ActionServer) Start() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ActionCallbackPath, a.handleAction)
+	logging.Logger.Info("Starting Mattermost action callback server on ", a.Addr)
+	return http.ListenAndServe(a.Addr, mux)
+}
+
+// handleAction reconstructs the target resource from the clicked action's
+// descriptor and runs the follow-up kubectl command, replying with an
+// ephemeral update
+func (a *ActionServer) handleAction(w http.ResponseWriter, r *http.Request) {
+	var req model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	actionID, _ := req.Context["action_id"].(string)
+	descriptor, ok := a.resolve(actionID)
+	if !ok {
+		a.reply(w, "This action has expired. Please re-run the original command.")
+		return
+	}
+
+	e := execute.NewDefaultExecutor(descriptor.command(), a.bot.AllowKubectl, descriptor.ChannelID, true, mattermostBindingOf(descriptor.Binding), execute.SourceSlashCommand)
+	a.reply(w, "```\n"+e.Execute()+"\n```")
+}
+
+// reply writes an ephemeral PostActionIntegrationResponse back to Mattermost
+func (a *ActionServer) reply(w http.ResponseWriter, ephemeralText string) {
+	w.Header().Set("Content-Type", "application/json")
+	resp := &model.PostActionIntegrationResponse{EphemeralText: ephemeralText}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Logger.Error("Failed to write action callback response. Error: ", err)
+	}
+}
+
+// register stores a resource descriptor and returns the action ID referencing
+// it, first sweeping out any descriptors whose TTL has passed
+func (a *ActionServer) register(d resourceDescriptor) string {
+	now := time.Now()
+	d.expiresAt = now.Add(actionDescriptorTTL)
+	id := newActionID()
+
+	a.mu.Lock()
+	for k, v := range a.descriptors {
+		if now.After(v.expiresAt) {
+			delete(a.descriptors, k)
+		}
+	}
+	a.descriptors[id] = d
+	a.mu.Unlock()
+	return id
+}
+
+// resolve returns the descriptor for an action ID and evicts it: each
+// button click is single-use, which also keeps the descriptor map from
+// growing unboundedly between TTL sweeps
+func (a *ActionServer) resolve(id string) (resourceDescriptor, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	d, ok := a.descriptors[id]
+	if !ok {
+		return resourceDescriptor{}, false
+	}
+	delete(a.descriptors, id)
+	if time.Now().After(d.expiresAt) {
+		return resourceDescriptor{}, false
+	}
+	return d, true
+}
+
+// newActionID returns a random identifier for a registered resource descriptor
+func newActionID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// podActionButtons are the follow-up commands offered on each pod row
+var podActionButtons = []struct {
+	label    string
+	verb     string
+	previous bool
+}{
+	{"Describe", "describe", false},
+	{"Logs", "logs", false},
+	{"Previous logs", "logs", true},
+	{"Delete", "delete", false},
+}
+
+// renderPodListWithActions builds a Mattermost attachment listing pods with
+// "Describe", "Logs", "Previous logs" and "Delete" action buttons on each row
+func (a *ActionServer) renderPodListWithActions(pods []string, binding *ChannelBinding, namespace, channelID string) *model.SlackAttachment {
+	actions := make([]*model.PostAction, 0, len(pods)*len(podActionButtons))
+	for _, pod := range pods {
+		for _, btn := range podActionButtons {
+			id := a.register(resourceDescriptor{
+				Verb:      btn.verb,
+				Previous:  btn.previous,
+				Resource:  "pod",
+				Name:      pod,
+				Namespace: namespace,
+				ChannelID: channelID,
+				Binding:   binding,
+			})
+
+			actions = append(actions, &model.PostAction{
+				Id:   id,
+				Name: fmt.Sprintf("%s %s", btn.label, pod),
+				Type: model.POST_ACTION_TYPE_BUTTON,
+				Integration: &model.PostActionIntegration{
+					URL: a.PublicURL + ActionCallbackPath,
+					Context: map[string]interface{}{
+						"action_id": id,
+					},
+				},
+			})
+		}
+	}
+
+	return &model.SlackAttachment{
+		Pretext: fmt.Sprintf("Pods in %s", namespace),
+		Actions: actions,
+	}
+}
@@ -0,0 +1,40 @@
+package mattermost
+
+import "testing"
+
+func TestResourceDescriptorCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		d    resourceDescriptor
+		want string
+	}{
+		{
+			name: "describe takes a TYPE NAME pair",
+			d:    resourceDescriptor{Verb: "describe", Resource: "pod", Name: "mypod", Namespace: "ns1"},
+			want: "describe pod mypod -n ns1",
+		},
+		{
+			name: "delete takes a TYPE NAME pair",
+			d:    resourceDescriptor{Verb: "delete", Resource: "pod", Name: "mypod", Namespace: "ns1"},
+			want: "delete pod mypod -n ns1",
+		},
+		{
+			name: "logs takes just NAME, no TYPE token",
+			d:    resourceDescriptor{Verb: "logs", Resource: "pod", Name: "mypod", Namespace: "ns1"},
+			want: "logs mypod -n ns1",
+		},
+		{
+			name: "logs --previous",
+			d:    resourceDescriptor{Verb: "logs", Previous: true, Resource: "pod", Name: "mypod", Namespace: "ns1"},
+			want: "logs --previous mypod -n ns1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.command(); got != tt.want {
+				t.Errorf("command() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
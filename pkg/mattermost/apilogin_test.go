@@ -0,0 +1,25 @@
+package mattermost
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// TestApiLoginPersonalAccessTokenMode covers the one apiLogin branch that
+// doesn't reach out to the network: a configured PersonalAccessToken takes
+// precedence and is applied directly to the API client.
+func TestApiLoginPersonalAccessTokenMode(t *testing.T) {
+	client = model.NewAPIv4Client("http://mattermost.example.invalid")
+	b := &Bot{PersonalAccessToken: "test-token"}
+
+	if err := b.apiLogin(); err != nil {
+		t.Fatalf("apiLogin() returned error: %v", err)
+	}
+	if client.AuthToken != "test-token" {
+		t.Errorf("client.AuthToken = %q, want %q", client.AuthToken, "test-token")
+	}
+	if client.AuthType != model.HEADER_BEARER {
+		t.Errorf("client.AuthType = %q, want %q", client.AuthType, model.HEADER_BEARER)
+	}
+}
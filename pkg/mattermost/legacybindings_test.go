@@ -0,0 +1,47 @@
+package mattermost
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/infracloudio/botkube/pkg/config"
+)
+
+func TestLegacyBindings(t *testing.T) {
+	tests := []struct {
+		name string
+		m    config.Mattermost
+		want []config.MattermostBinding
+	}{
+		{
+			name: "bindings set takes precedence",
+			m: config.Mattermost{
+				Team:    "legacy-team",
+				Channel: "legacy-channel",
+				Bindings: []config.MattermostBinding{
+					{Team: "t1", Channel: "c1"},
+				},
+			},
+			want: []config.MattermostBinding{{Team: "t1", Channel: "c1"}},
+		},
+		{
+			name: "legacy team/channel synthesized when bindings unset",
+			m:    config.Mattermost{Team: "legacy-team", Channel: "legacy-channel"},
+			want: []config.MattermostBinding{{Team: "legacy-team", Channel: "legacy-channel", ClusterName: "prod"}},
+		},
+		{
+			name: "neither set yields no bindings",
+			m:    config.Mattermost{},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := legacyBindings(tt.m, "prod")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("legacyBindings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package mattermost
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru"
 
 	"github.com/infracloudio/botkube/pkg/config"
 	"github.com/infracloudio/botkube/pkg/execute"
@@ -13,8 +19,7 @@ import (
 var client *model.Client4
 var webSocketClient *model.WebSocketClient
 var botUser *model.User
-var botTeam *model.Team
-var botChannel *model.Channel
+var seenPosts *lru.Cache
 
 const (
 	// BotName stores Botkube details
@@ -25,16 +30,61 @@ const (
 	WebSocketProtocol = "ws:"
 	// Logs file name
 	Logs = "logs"
+	// reconnectMinBackoff is the initial delay before a reconnect attempt
+	reconnectMinBackoff = 2 * time.Second
+	// reconnectMaxBackoff caps the exponential backoff between reconnect attempts
+	reconnectMaxBackoff = 2 * time.Minute
+	// defaultDedupeCacheSize is the number of recent post digests remembered
+	// to skip replayed websocket events, used when DedupeCacheSize is unset
+	defaultDedupeCacheSize = 100
 )
 
+// ChannelBinding pairs a resolved Mattermost team/channel with the
+// per-channel authorization config matched to it
+type ChannelBinding struct {
+	config.MattermostBinding
+	TeamID    string
+	ChannelID string
+}
+
 // Bot listens for user's message, execute commands and sends back the response
 type Bot struct {
-	ServerURL    string
-	Token        string
-	TeamName     string
-	ChannelName  string
-	ClusterName  string
-	AllowKubectl bool
+	ServerURL           string
+	Token               string
+	Login               string
+	Password            string
+	PersonalAccessToken string
+	Bindings            []config.MattermostBinding
+	AllowKubectl        bool
+	DedupeCacheSize     int
+
+	// channelsMu guards channels, which is replaced by the websocket
+	// supervisor goroutine on every reconnect while the slash-command and
+	// action HTTP handlers read it concurrently
+	channelsMu sync.RWMutex
+	// channels maps a resolved Mattermost channel ID to the binding that
+	// authorized it, populated by resolveBindings on (re)connect
+	channels map[string]*ChannelBinding
+
+	// actions renders and resolves the Describe/Logs/Delete follow-up
+	// buttons attached to `kubectl get pods` responses
+	actions *ActionServer
+	// slashCommands serves the /kubectl and /botkube slash commands
+	slashCommands *SlashCommandServer
+}
+
+// setChannels atomically replaces the resolved channel bindings
+func (b *Bot) setChannels(channels map[string]*ChannelBinding) {
+	b.channelsMu.Lock()
+	b.channels = channels
+	b.channelsMu.Unlock()
+}
+
+// channelBinding returns the binding resolved for channelID, if any
+func (b *Bot) channelBinding(channelID string) *ChannelBinding {
+	b.channelsMu.RLock()
+	defer b.channelsMu.RUnlock()
+	return b.channels[channelID]
 }
 
 // NewMattermostBot returns new Bot object
@@ -44,14 +94,37 @@ func NewMattermostBot() *Bot {
 		logging.Logger.Fatal(fmt.Sprintf("Error in loading configuration. Error:%s", err.Error()))
 	}
 
-	return &Bot{
-		ServerURL:    c.Communications.Mattermost.URL,
-		Token:        c.Communications.Mattermost.Token,
-		TeamName:     c.Communications.Mattermost.Team,
-		ChannelName:  c.Communications.Mattermost.Channel,
-		ClusterName:  c.Settings.ClusterName,
-		AllowKubectl: c.Settings.AllowKubectl,
+	b := &Bot{
+		ServerURL:           c.Communications.Mattermost.URL,
+		Token:               c.Communications.Mattermost.Token,
+		Login:               c.Communications.Mattermost.Login,
+		Password:            c.Communications.Mattermost.Password,
+		PersonalAccessToken: c.Communications.Mattermost.PersonalAccessToken,
+		Bindings:            legacyBindings(c.Communications.Mattermost, c.Settings.ClusterName),
+		AllowKubectl:        c.Settings.AllowKubectl,
+		DedupeCacheSize:     c.Communications.Mattermost.MessageHistoryLimit,
 	}
+	b.actions = NewActionServer(b)
+	b.slashCommands = NewSlashCommandServer(b)
+	return b
+}
+
+// legacyBindings returns m.Bindings unchanged when set. Otherwise, for a
+// deployment still configured with the older single team/channel fields,
+// it synthesizes the equivalent single binding so those deployments keep
+// working unchanged instead of silently losing their channel.
+func legacyBindings(m config.Mattermost, clusterName string) []config.MattermostBinding {
+	if len(m.Bindings) > 0 {
+		return m.Bindings
+	}
+	if m.Team == "" || m.Channel == "" {
+		return nil
+	}
+	return []config.MattermostBinding{{
+		Team:        m.Team,
+		Channel:     m.Channel,
+		ClusterName: clusterName,
+	}}
 }
 
 // Channel structure in Mattermost
@@ -68,7 +141,18 @@ func mmChannel(channelName, teamID string) *model.Channel {
 // Start establishes mattermost connection and listens for messages
 func (b *Bot) Start() {
 	client = model.NewAPIv4Client(b.ServerURL)
-	client.SetOAuthToken(b.Token)
+
+	dedupeCacheSize := b.DedupeCacheSize
+	if dedupeCacheSize <= 0 {
+		dedupeCacheSize = defaultDedupeCacheSize
+	}
+	seenPosts, _ = lru.New(dedupeCacheSize)
+
+	// Authenticate against the Mattermost server with whichever mode is configured
+	if err := b.apiLogin(); err != nil {
+		logging.Logger.Error("There was a problem authenticating with the Mattermost server. Error: ", err)
+		return
+	}
 
 	// Check connection to Mattermost server
 	err := checkServerConnection()
@@ -77,40 +161,157 @@ func (b *Bot) Start() {
 		return
 	}
 
-	// Check Team exists and get Team ID
-	botTeam, err = getBotTeam(b.TeamName)
+	// Resolve every configured team/channel binding, creating channels and
+	// joining the bot user as needed
+	channels, err := b.resolveBindings()
 	if err != nil {
-		logging.Logger.Error("There was a problem finding Mattermost team. Error: ", err)
+		logging.Logger.Error("There was a problem resolving Mattermost bindings. Error: ", err)
 		return
 	}
+	b.setChannels(channels)
 
-	// Check Botkube user exists and get User ID
-	botUser, err = getBotUser(botTeam.Id)
-	if err != nil {
-		logging.Logger.Error("There was a problem creating user in Mattermost. Error: ", err)
+	// Create WebSocketClient and handle messages
+	if err := b.connectWebSocket(); err != nil {
+		logging.Logger.Error("There was a problem connecting to the Mattermost websocket. Error: ", err)
 		return
 	}
+	go b.superviseWebSocket()
 
-	// Check Channel exists or create Channel and add user to the Channel
-	botChannel, err = getBotChannel(b.ChannelName, botTeam.Id, botUser.Id)
-	if err != nil {
-		logging.Logger.Error("There was a problem creating channel. Error: ", err)
-		return
+	// Serve the slash-command and action-callback HTTP endpoints alongside
+	// the websocket listener
+	go func() {
+		if err := b.slashCommands.Start(); err != nil {
+			logging.Logger.Error("Mattermost slash command server stopped. Error: ", err)
+		}
+	}()
+	go func() {
+		if err := b.actions.Start(); err != nil {
+			logging.Logger.Error("Mattermost action callback server stopped. Error: ", err)
+		}
+	}()
+	return
+}
+
+// apiLogin authenticates the API client using whichever mode is configured,
+// in order of precedence: personal access token, login/password, static OAuth token
+func (b *Bot) apiLogin() error {
+	switch {
+	case b.PersonalAccessToken != "":
+		client.AuthToken = b.PersonalAccessToken
+		client.AuthType = model.HEADER_BEARER
+	case b.Login != "" && b.Password != "":
+		if _, resp := client.Login(b.Login, b.Password); resp.Error != nil {
+			return resp.Error
+		}
+	default:
+		client.SetOAuthToken(b.Token)
 	}
+	return nil
+}
 
-	// Create WebSocketClient and handle messages
+// connectWebSocket opens the websocket connection and starts listening on it
+func (b *Bot) connectWebSocket() error {
 	webSocketURL := WebSocketProtocol + strings.SplitN(b.ServerURL, ":", 2)[1]
-	webSocketClient, _ := model.NewWebSocketClient4(webSocketURL, client.AuthToken)
+	wsClient, err := model.NewWebSocketClient4(webSocketURL, client.AuthToken)
+	if err != nil {
+		return err
+	}
+	webSocketClient = wsClient
 	webSocketClient.Listen()
-	go func() {
-		for {
-			select {
-			case event := <-webSocketClient.EventChannel:
-				handleMessage(event, b.AllowKubectl, b.ClusterName, b.ChannelName)
+	return nil
+}
+
+// superviseWebSocket reads incoming events off the websocket and, when the
+// connection drops, keeps reconnecting with an exponential backoff until the
+// listener is healthy again
+func (b *Bot) superviseWebSocket() {
+	for {
+		// The client closes EventChannel when its listener stops and records
+		// the cause in ListenError
+		event, ok := <-webSocketClient.EventChannel
+		if !ok {
+			logging.Logger.Error("Mattermost websocket disconnected. Error: ", webSocketClient.ListenError)
+			b.reconnectWithBackoff()
+			continue
+		}
+		b.handleMessage(event)
+	}
+}
+
+// reconnectWithBackoff retries reconnect with an exponential backoff until
+// it succeeds, so a server restart or outage never permanently kills the
+// supervisor goroutine
+func (b *Bot) reconnectWithBackoff() {
+	backoff := reconnectMinBackoff
+	for {
+		time.Sleep(backoff)
+		if err := b.reconnect(); err == nil {
+			return
+		} else {
+			logging.Logger.Error("Failed to reconnect to Mattermost, retrying. Error: ", err)
+		}
+		if backoff *= 2; backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
+
+// reconnect re-authenticates, rejoins every configured team/channel binding
+// and re-establishes the websocket connection after a disconnect
+func (b *Bot) reconnect() error {
+	logging.Logger.Info("Reconnecting to Mattermost")
+	if err := b.apiLogin(); err != nil {
+		return err
+	}
+
+	channels, err := b.resolveBindings()
+	if err != nil {
+		return err
+	}
+	b.setChannels(channels)
+
+	return b.connectWebSocket()
+}
+
+// resolveBindings looks up (creating if necessary) every team/channel pair
+// configured in b.Bindings, joins the bot user to each channel, and returns
+// the result keyed by the resolved channel ID
+func (b *Bot) resolveBindings() (map[string]*ChannelBinding, error) {
+	channels := make(map[string]*ChannelBinding)
+	teams := make(map[string]*model.Team)
+
+	for _, binding := range b.Bindings {
+		team, ok := teams[binding.Team]
+		if !ok {
+			t, err := getBotTeam(binding.Team)
+			if err != nil {
+				return nil, fmt.Errorf("finding Mattermost team %q: %s", binding.Team, err)
 			}
+			team = t
+			teams[binding.Team] = team
 		}
-	}()
-	return
+
+		if botUser == nil {
+			u, err := getBotUser(team.Id)
+			if err != nil {
+				return nil, fmt.Errorf("finding Botkube user in team %q: %s", binding.Team, err)
+			}
+			botUser = u
+		}
+
+		channel, err := getBotChannel(binding.Channel, team.Id, botUser.Id)
+		if err != nil {
+			return nil, fmt.Errorf("resolving channel %q in team %q: %s", binding.Channel, binding.Team, err)
+		}
+
+		channels[channel.Id] = &ChannelBinding{
+			MattermostBinding: binding,
+			TeamID:            team.Id,
+			ChannelID:         channel.Id,
+		}
+	}
+
+	return channels, nil
 }
 
 // Check if Mattermost server is reachable
@@ -156,33 +357,165 @@ func getBotChannel(channelName, botTeamID, botUserID string) (*model.Channel, er
 	return botChannel, nil
 }
 
+// alreadySeen reports whether the digest of rawPost was seen within the last
+// N messages, adding it to the cache if not. This tree has no Slack bot
+// package to mirror the dedupe onto; wire the same pattern into its
+// entry-point handler if/when one is added here.
+func alreadySeen(rawPost string) bool {
+	digest := sha256.Sum256([]byte(rawPost))
+	seen, _ := seenPosts.ContainsOrAdd(hex.EncodeToString(digest[:]), true)
+	return seen
+}
+
+// directChannelType is the Mattermost channel_type for a direct message channel
+const directChannelType = "D"
+
 // Check incomming message and take action
-func handleMessage(event *model.WebSocketEvent, allowkubectl bool, clusterName, channelName string) {
+func (b *Bot) handleMessage(event *model.WebSocketEvent) {
 	// Check incomming message event type
 	if event.Event != model.WEBSOCKET_EVENT_POSTED {
 		return
 	}
-	post := model.PostFromJson(strings.NewReader(event.Data["post"].(string)))
 
-	// Check if message posted by botkube and has @botkube prefix
-	if post.UserId == botUser.Id || !(strings.HasPrefix(post.Message, "@"+BotName+" ")) {
+	// Mattermost occasionally replays posts (HA failover, network hiccups), so
+	// skip any post we've already executed recently
+	rawPost, ok := event.Data["post"].(string)
+	if !ok {
+		return
+	}
+	if alreadySeen(rawPost) {
+		logging.Logger.Info("Duplicate post detected. Skipping execution")
+		return
+	}
+
+	post := model.PostFromJson(strings.NewReader(rawPost))
+	if post.UserId == botUser.Id {
+		return
+	}
+
+	// A direct message to the bot doesn't require the "@botkube " prefix
+	isDM := event.Data["channel_type"] == directChannelType
+	var inMessage string
+	switch {
+	case isDM:
+		inMessage = strings.TrimSpace(post.Message)
+	case strings.HasPrefix(post.Message, "@"+BotName+" "):
+		inMessage = strings.TrimPrefix(post.Message, "@"+BotName+" ")
+	default:
+		return
+	}
+	if len(inMessage) == 0 {
 		return
 	}
-	inMessage := strings.TrimPrefix(post.Message, "@"+BotName+" ")
 
-	// Check where the message is posted
-	isAuthChannel := false
-	if event.Broadcast.ChannelId == botChannel.Id {
-		isAuthChannel = true
+	// A channel with no matching binding is unauthorized unless it's a DM
+	binding := b.channelBinding(event.Broadcast.ChannelId)
+	isAuthChannel := isDM || binding != nil
+	source := execute.SourceChannelMessage
+	if isDM {
+		source = execute.SourceDirectMessage
 	}
 
-	e := execute.NewDefaultExecutor(inMessage, allowkubectl, clusterName, channelName, isAuthChannel)
+	e := execute.NewDefaultExecutor(inMessage, b.AllowKubectl, event.Broadcast.ChannelId, isAuthChannel, mattermostBindingOf(binding), source)
 	outMessage := e.Execute()
 	if len(outMessage) == 0 {
 		logging.Logger.Info("Invalid request. Dumping the response")
 		return
 	}
-	sendMessage("`"+outMessage+"`", post.Id, event.Broadcast.ChannelId)
+	b.respond(inMessage, outMessage, binding, post.Id, event.Broadcast.ChannelId)
+}
+
+// mattermostBindingOf returns the config binding carried by a resolved
+// ChannelBinding, or nil when there is none (e.g. a DM or unbound channel)
+func mattermostBindingOf(binding *ChannelBinding) *config.MattermostBinding {
+	if binding == nil {
+		return nil
+	}
+	return &binding.MattermostBinding
+}
+
+// respond renders the executor output for the channel it's headed to: a
+// `get pods` response gets Describe/Logs/Delete action buttons attached, a
+// `get -o wide` response gets a plain rich attachment, and everything else
+// gets a plain code block
+func (b *Bot) respond(cmd, outMessage string, binding *ChannelBinding, postID, channelID string) {
+	clusterName := ""
+	if binding != nil {
+		clusterName = binding.ClusterName
+	}
+
+	if meta, ok := wideGetCommand(cmd); ok {
+		attachment := renderAttachment(cmd, outMessage, clusterName, meta.namespace, meta.verb)
+		if meta.resource == "pods" || meta.resource == "pod" || meta.resource == "po" {
+			attachment.Actions = b.actions.renderPodListWithActions(podNamesFromOutput(outMessage), binding, meta.namespace, channelID).Actions
+		}
+		sendAttachment(attachment, postID, channelID)
+		return
+	}
+	sendMessage("```\n"+outMessage+"\n```", postID, channelID)
+}
+
+// commandMeta holds the bits of a kubectl invocation worth surfacing as
+// attachment fields
+type commandMeta struct {
+	verb      string
+	resource  string
+	namespace string
+}
+
+// wideGetCommand reports whether cmd is a `get` invocation worth rendering
+// as an attachment - either a `get pods` listing (eligible for action
+// buttons) or a `get ... -o wide` table - and, if so, its verb, resource
+// and target namespace
+func wideGetCommand(cmd string) (commandMeta, bool) {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 || fields[0] != "get" {
+		return commandMeta{}, false
+	}
+	resource := fields[1]
+	if resource != "pods" && resource != "pod" && resource != "po" && !strings.Contains(cmd, "-o wide") {
+		return commandMeta{}, false
+	}
+	namespace := "default"
+	for i, f := range fields {
+		if (f == "-n" || f == "--namespace") && i+1 < len(fields) {
+			namespace = fields[i+1]
+		}
+	}
+	return commandMeta{verb: fields[0], resource: resource, namespace: namespace}, true
+}
+
+// podNamesFromOutput extracts pod names from the first column of tabular
+// `kubectl get pods` output, skipping the header row
+func podNamesFromOutput(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+	pods := make([]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pods = append(pods, fields[0])
+	}
+	return pods
+}
+
+// renderAttachment formats command output as a Mattermost attachment with a
+// code-fenced table and cluster/namespace/verb fields
+func renderAttachment(cmd, outMessage, clusterName, namespace, verb string) *model.SlackAttachment {
+	return &model.SlackAttachment{
+		Fallback: outMessage,
+		Pretext:  fmt.Sprintf("Result of `%s`", cmd),
+		Text:     "```\n" + outMessage + "\n```",
+		Fields: []*model.SlackAttachmentField{
+			{Title: "Cluster", Value: clusterName, Short: true},
+			{Title: "Namespace", Value: namespace, Short: true},
+			{Title: "Verb", Value: verb, Short: true},
+		},
+	}
 }
 
 // Send messages to Mattermost
@@ -206,3 +539,13 @@ func sendMessage(msg, postID, channelID string) {
 		logging.Logger.Error("Failed to send message. Error: ", resp.Error)
 	}
 }
+
+// sendAttachment posts a rendered attachment as a reply in the Channel
+func sendAttachment(attachment *model.SlackAttachment, postID, channelID string) {
+	post := &model.Post{ChannelId: channelID, RootId: postID}
+	post.AddProp("attachments", []*model.SlackAttachment{attachment})
+
+	if _, resp := client.CreatePost(post); resp.Error != nil {
+		logging.Logger.Error("Failed to send attachment. Error: ", resp.Error)
+	}
+}
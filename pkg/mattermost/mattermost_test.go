@@ -0,0 +1,89 @@
+package mattermost
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWideGetCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		cmd    string
+		want   commandMeta
+		wantOk bool
+	}{
+		{
+			name:   "get pods is eligible for actions",
+			cmd:    "get pods -n kube-system",
+			want:   commandMeta{verb: "get", resource: "pods", namespace: "kube-system"},
+			wantOk: true,
+		},
+		{
+			name:   "get pods defaults to the default namespace",
+			cmd:    "get pods",
+			want:   commandMeta{verb: "get", resource: "pods", namespace: "default"},
+			wantOk: true,
+		},
+		{
+			name:   "get -o wide is eligible without being pods",
+			cmd:    "get deployments -o wide -n ns1",
+			want:   commandMeta{verb: "get", resource: "deployments", namespace: "ns1"},
+			wantOk: true,
+		},
+		{
+			name:   "plain get without -o wide is not eligible",
+			cmd:    "get deployments -n ns1",
+			wantOk: false,
+		},
+		{
+			name:   "non-get command is not eligible",
+			cmd:    "describe pod mypod",
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := wideGetCommand(tt.cmd)
+			if ok != tt.wantOk {
+				t.Fatalf("wideGetCommand(%q) ok = %v, want %v", tt.cmd, ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("wideGetCommand(%q) = %+v, want %+v", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodNamesFromOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name:   "header and rows",
+			output: "NAME       READY   STATUS\nmypod-1    1/1     Running\nmypod-2    1/1     Running\n",
+			want:   []string{"mypod-1", "mypod-2"},
+		},
+		{
+			name:   "header only",
+			output: "NAME   READY   STATUS",
+			want:   nil,
+		},
+		{
+			name:   "empty output",
+			output: "",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := podNamesFromOutput(tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("podNamesFromOutput(%q) = %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
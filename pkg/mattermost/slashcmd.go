@@ -0,0 +1,82 @@
+package mattermost
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/infracloudio/botkube/pkg/config"
+	"github.com/infracloudio/botkube/pkg/execute"
+	"github.com/infracloudio/botkube/pkg/logging"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// SlashCommandPath is the HTTP path Mattermost slash commands POST to
+const SlashCommandPath = "/mattermost/command"
+
+// SlashCommandServer serves the Mattermost `/kubectl` and `/botkube` slash commands
+type SlashCommandServer struct {
+	Addr  string
+	Token string
+
+	// bot resolves the channel the command came from to its authorization
+	// binding, shared with the websocket listener
+	bot *Bot
+}
+
+// NewSlashCommandServer returns a new SlashCommandServer bound to b, configured from Botkube config
+func NewSlashCommandServer(b *Bot) *SlashCommandServer {
+	c, err := config.New()
+	if err != nil {
+		logging.Logger.Fatal(fmt.Sprintf("Error in loading configuration. Error:%s", err.Error()))
+	}
+
+	return &SlashCommandServer{
+		Addr:  c.Communications.Mattermost.SlashCommandAddr,
+		Token: c.Communications.Mattermost.SlashCommandToken,
+		bot:   b,
+	}
+}
+
+// Start registers the slash command handler and serves it over HTTP
+func (s *SlashCommandServer) Start() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc(SlashCommandPath, s.handleSlashCommand)
+	logging.Logger.Info("Starting Mattermost slash command server on ", s.Addr)
+	return http.ListenAndServe(s.Addr, mux)
+}
+
+// handleSlashCommand validates the request token, executes the command and
+// replies with a model.CommandResponse
+func (s *SlashCommandServer) handleSlashCommand(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("token") != s.Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	channelID := r.FormValue("channel_id")
+	binding := s.bot.channelBinding(channelID)
+
+	inMessage := strings.TrimSpace(r.FormValue("text"))
+	e := execute.NewDefaultExecutor(inMessage, s.bot.AllowKubectl, channelID, binding != nil, mattermostBindingOf(binding), execute.SourceSlashCommand)
+	outMessage := e.Execute()
+	if len(outMessage) == 0 {
+		outMessage = "Invalid request"
+	}
+
+	resp := &model.CommandResponse{
+		ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL,
+		Text:         "```\n" + outMessage + "\n```",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Logger.Error("Failed to write slash command response. Error: ", err)
+	}
+}